@@ -12,6 +12,7 @@ import (
 type smallMessage struct {
 	MessageID string
 	PostedAt  time.Time
+	AuthorID  string
 }
 
 const minTimeBetweenDeletion = time.Second * 5
@@ -45,13 +46,16 @@ type ManagedChannel struct {
 	// Observed in the return of collectMessagesToDelete.
 	killBit bool
 
+	// Additional filters evaluated in AddMessage and LoadBacklog; any filter
+	// that returns true for a message protects it like a pin does.
+	filters []MessageFilter
+
 	// if false, need to check channel history for messages
 	isStarted chan struct{}
-	// liveMessages contains a list of message IDs and the timestamp they
-	// were posted at, listing the candidates for deletion in this channel.
-	// It should always be sorted with the oldest messages at index 0 and
-	// the newer messages at higher indices.
-	liveMessages []smallMessage
+	// liveMessages indexes the candidates for deletion in this channel:
+	// chronological order (oldest first) plus lookups by message ID and by
+	// author, so deletion-path code doesn't need to scan the whole backlog.
+	liveMessages *messageIndex
 	// Set of message IDs that need to be kept and not deleted.
 	keepLookup map[string]bool
 	// Used in queue.go for exponential backoff
@@ -67,6 +71,10 @@ func InitChannel(b *Bot, chConf ManagedChannelMarshal) (*ManagedChannel, error)
 	if disCh.GuildID != chConf.GuildID {
 		needsExport = true
 	}
+	filters, err := unmarshalFilters(chConf.Filters)
+	if err != nil {
+		return nil, err
+	}
 	return &ManagedChannel{
 		bot:             b,
 		ChannelID:       disCh.ID,
@@ -79,8 +87,9 @@ func InitChannel(b *Bot, chConf ManagedChannelMarshal) (*ManagedChannel, error)
 		KeepMessages:    chConf.KeepMessages,
 		IsDonor:         chConf.IsDonor,
 		needsExport:     needsExport,
+		filters:         filters,
 		isStarted:       make(chan struct{}),
-		liveMessages:    nil,
+		liveMessages:    newMessageIndex(),
 		keepLookup:      make(map[string]bool),
 	}, nil
 }
@@ -97,9 +106,42 @@ func (c *ManagedChannel) Export() ManagedChannelMarshal {
 		LastSentUpdate: c.LastSentUpdate,
 		KeepMessages:   c.KeepMessages,
 		IsDonor:        c.IsDonor,
+		Filters:        marshalFilters(c.filters),
 	}
 }
 
+// SetFilters replaces the channel's filter chain.
+func (c *ManagedChannel) SetFilters(filters []MessageFilter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.filters = filters
+}
+
+// shouldKeepMessage reports whether any of the channel's filters protects m
+// from autodeletion. Must be called with c.mu already held.
+func (c *ManagedChannel) shouldKeepMessage(m *discordgo.Message) bool {
+	return anyFilterKeeps(c.filters, m)
+}
+
+// filtersSnapshot returns the channel's current filter chain slice for use
+// somewhere that can't hold c.mu for the duration (e.g. across a series of
+// Discord API calls). The slice is not copied; SetFilters replaces it rather
+// than modifying it in place.
+func (c *ManagedChannel) filtersSnapshot() []MessageFilter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.filters
+}
+
+func anyFilterKeeps(filters []MessageFilter, m *discordgo.Message) bool {
+	for _, f := range filters {
+		if f.ShouldKeep(m) {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *ManagedChannel) String() string {
 	return fmt.Sprintf("%s #%s", c.ChannelID, c.ChannelName)
 }
@@ -116,7 +158,7 @@ func (c *ManagedChannel) Disable() {
 
 	// reset internal state
 	c.mu.Lock()
-	c.liveMessages = nil
+	c.liveMessages = newMessageIndex()
 	c.keepLookup = nil
 
 	c.killBit = true // ensure reapq gets our drop message
@@ -153,8 +195,21 @@ func (c *ManagedChannel) loadPins() ([]*discordgo.Message, error) {
 	}
 }
 
+// maxBacklogFullPages bounds how many 100-message pages LoadBacklogNow will
+// fetch for a channel whose MaxMessages exceeds backlogReloadLimit.
+const maxBacklogFullPages = 20
+
 func (c *ManagedChannel) LoadBacklogNow() {
-	err := c.LoadBacklog()
+	c.mu.Lock()
+	maxMessages := c.MaxMessages
+	c.mu.Unlock()
+
+	var err error
+	if maxMessages > backlogReloadLimit {
+		err = c.LoadBacklogFull(maxBacklogFullPages)
+	} else {
+		err = c.LoadBacklog()
+	}
 	if isRetryableLoadError(err) {
 		c.bot.QueueLoadBacklog(c, true)
 	}
@@ -215,7 +270,7 @@ func (c *ManagedChannel) LoadBacklog() error {
 		c.keepLookup[v] = true
 	}
 
-	c.liveMessages = make([]smallMessage, 0, len(msgs))
+	c.liveMessages = newMessageIndex()
 	// Iterate backwards so we swap the order
 	for i := len(msgs); i > 0; i-- {
 		v := msgs[i-1]
@@ -224,6 +279,9 @@ func (c *ManagedChannel) LoadBacklog() error {
 		if c.keepLookup[v.ID] {
 			continue
 		}
+		if c.shouldKeepMessage(v) {
+			continue
+		}
 
 		ts, err := v.Timestamp.Parse()
 		if err != nil {
@@ -232,9 +290,14 @@ func (c *ManagedChannel) LoadBacklog() error {
 		if ts.IsZero() {
 			continue
 		}
-		c.liveMessages = append(c.liveMessages, smallMessage{
+		authorID := ""
+		if v.Author != nil {
+			authorID = v.Author.ID
+		}
+		c.liveMessages.PushBack(smallMessage{
 			MessageID: v.ID,
 			PostedAt:  ts,
+			AuthorID:  authorID,
 		})
 	}
 
@@ -246,7 +309,123 @@ func (c *ManagedChannel) LoadBacklog() error {
 		close(c.isStarted)
 		inited = "initialized"
 	}
-	fmt.Printf("[load] %s %s, %d msgs %d keeps\n", c.String(), inited, len(c.liveMessages), len(c.keepLookup))
+	fmt.Printf("[load] %s %s, %d msgs %d keeps\n", c.String(), inited, c.liveMessages.Len(), len(c.keepLookup))
+	return nil
+}
+
+// backlogPageBackoffFloor/Ceil bound the delay between pages in
+// LoadBacklogFull, doubling each page to respect Discord's rate limits.
+const backlogPageBackoffFloor = 250 * time.Millisecond
+const backlogPageBackoffCeil = 8 * time.Second
+
+// LoadBacklogFull is LoadBacklog's single-page fetch widened to iterate
+// ChannelMessages pages via before= until a message falls outside
+// MessageLiveTime or maxPages is reached. LoadBacklog can't track more than
+// 100 messages, so a channel configured with MaxMessages > 100 silently
+// never reaps past the 100th; this is also used to recover the full live
+// window after a long bot outage.
+func (c *ManagedChannel) LoadBacklogFull(maxPages int) error {
+	// prevent reentrancy, even during web requests
+	c.backlogMu.Lock()
+	defer c.backlogMu.Unlock()
+
+	pins, err := c.loadPins()
+	if err != nil {
+		fmt.Println("[ERR ] could not load pins for", c, err)
+		return err
+	}
+
+	keepLookup := make(map[string]bool)
+	for i := range pins {
+		keepLookup[pins[i].ID] = true
+	}
+	for _, v := range c.KeepMessages {
+		keepLookup[v] = true
+	}
+
+	c.mu.Lock()
+	liveTime := c.MessageLiveTime
+	c.mu.Unlock()
+
+	var cutoff time.Time
+	if liveTime > 0 {
+		cutoff = time.Now().Add(-liveTime)
+	}
+	filters := c.filtersSnapshot()
+
+	var collected []smallMessage
+	before := ""
+	backoff := backlogPageBackoffFloor
+	for page := 0; page < maxPages; page++ {
+		msgs, err := c.bot.s.ChannelMessages(c.ChannelID, 100, before, "", "")
+		if err != nil {
+			fmt.Println("[ERR ] could not load backlog page for", c, err)
+			return err
+		}
+		if len(msgs) == 0 {
+			break
+		}
+
+		pastCutoff := false
+		for _, v := range msgs {
+			if keepLookup[v.ID] {
+				continue
+			}
+			if anyFilterKeeps(filters, v) {
+				continue
+			}
+
+			ts, err := v.Timestamp.Parse()
+			if err != nil {
+				panic("Timestamp format change")
+			}
+			if ts.IsZero() {
+				continue
+			}
+			if !cutoff.IsZero() && ts.Before(cutoff) {
+				pastCutoff = true
+				break
+			}
+
+			authorID := ""
+			if v.Author != nil {
+				authorID = v.Author.ID
+			}
+			collected = append(collected, smallMessage{MessageID: v.ID, PostedAt: ts, AuthorID: authorID})
+		}
+
+		before = msgs[len(msgs)-1].ID
+		if pastCutoff || len(msgs) < 100 {
+			break
+		}
+
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > backlogPageBackoffCeil {
+			backoff = backlogPageBackoffCeil
+		}
+	}
+
+	defer c.bot.QueueReap(c) // requires mutex unlocked
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.keepLookup = keepLookup
+	c.liveMessages = newMessageIndex()
+	// collected is newest-first (page order); push oldest-first like
+	// LoadBacklog does.
+	for i := len(collected); i > 0; i-- {
+		c.liveMessages.PushBack(collected[i-1])
+	}
+
+	inited := "reloaded"
+	select {
+	case <-c.isStarted:
+	default:
+		close(c.isStarted)
+		inited = "initialized"
+	}
+	fmt.Printf("[load] %s %s (full), %d msgs %d keeps\n", c.String(), inited, c.liveMessages.Len(), len(c.keepLookup))
 	return nil
 }
 
@@ -274,16 +453,25 @@ func (c *ManagedChannel) AddMessage(m *discordgo.Message) {
 		c.mu.Unlock()
 		return
 	}
+	if c.shouldKeepMessage(m) {
+		c.mu.Unlock()
+		return
+	}
 
-	if len(c.liveMessages) == 0 {
+	if c.liveMessages.Len() == 0 {
 		needReap = true
-	} else if c.MaxMessages > 0 && len(c.liveMessages) == c.MaxMessages {
+	} else if c.MaxMessages > 0 && c.liveMessages.Len() == c.MaxMessages {
 		needReap = true
 	}
 
-	c.liveMessages = append(c.liveMessages, smallMessage{
+	authorID := ""
+	if m.Author != nil {
+		authorID = m.Author.ID
+	}
+	c.liveMessages.PushBack(smallMessage{
 		MessageID: m.ID,
 		PostedAt:  time.Now(),
+		AuthorID:  authorID,
 	})
 	c.mu.Unlock()
 
@@ -341,22 +529,10 @@ func (c *ManagedChannel) UpdatePins(newLpts string) {
 func (c *ManagedChannel) DoNotDeleteMessage(msgID string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	idx := -1
 
-	for i, v := range c.liveMessages {
-		if v.MessageID == msgID {
-			idx = i
-		}
-	}
-	if idx == -1 {
+	if c.liveMessages.Remove(msgID) == nil {
 		fmt.Println("[BUG] DoNotDeleteMessage called with non-live message")
-		return
 	}
-	lenMinus1 := len(c.liveMessages) - 1
-	// Delete item
-	copy(c.liveMessages[idx:], c.liveMessages[idx+1:])
-	c.liveMessages[lenMinus1] = smallMessage{}
-	c.liveMessages = c.liveMessages[:lenMinus1]
 }
 
 func (c *ManagedChannel) Enabled() bool {
@@ -381,23 +557,23 @@ func (c *ManagedChannel) GetNextDeletionTime() time.Time {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	for len(c.liveMessages) > 0 {
+	for c.liveMessages.Len() > 0 {
 		// Recheck keepLookup
-		if c.keepLookup[c.liveMessages[0].MessageID] {
-			c.liveMessages = c.liveMessages[1:]
+		if c.keepLookup[c.liveMessages.Front().MessageID] {
+			c.liveMessages.PopFront()
 			continue
 		}
 		break
 	}
-	if len(c.liveMessages) == 0 {
+	if c.liveMessages.Len() == 0 {
 		return time.Now().Add(240 * time.Hour)
 	}
 
-	if c.MaxMessages > 0 && len(c.liveMessages) > c.MaxMessages {
+	if c.MaxMessages > 0 && c.liveMessages.Len() > c.MaxMessages {
 		return c.minNextDelete
 	}
 	if c.MessageLiveTime != 0 {
-		ts := c.liveMessages[0].PostedAt.Add(c.MessageLiveTime)
+		ts := c.liveMessages.Front().PostedAt.Add(c.MessageLiveTime)
 		if ts.Before(c.minNextDelete) {
 			return c.minNextDelete
 		}
@@ -474,38 +650,41 @@ func (c *ManagedChannel) collectMessagesToDelete() (m []string, needsQueueBacklo
 	var oldest time.Time
 	var zero time.Time
 
-	nLiveMessages := len(c.liveMessages)
+	nLiveMessages := c.liveMessages.Len()
 
 	if c.MaxMessages > 0 {
-		for len(c.liveMessages) > c.MaxMessages {
-			if !c.keepLookup[c.liveMessages[0].MessageID] {
-				toDelete = append(toDelete, c.liveMessages[0].MessageID)
+		for c.liveMessages.Len() > c.MaxMessages {
+			front := c.liveMessages.Front()
+			if !c.keepLookup[front.MessageID] {
+				toDelete = append(toDelete, front.MessageID)
 				if oldest == zero {
-					oldest = c.liveMessages[0].PostedAt
+					oldest = front.PostedAt
 				}
 			}
-			c.liveMessages = c.liveMessages[1:]
+			c.liveMessages.PopFront()
 		}
 	}
 	if c.MessageLiveTime > 0 {
 		cutoff := time.Now().Add(-c.MessageLiveTime)
-		for len(c.liveMessages) > 0 && c.liveMessages[0].PostedAt.Before(cutoff) {
-			if !c.keepLookup[c.liveMessages[0].MessageID] {
-				toDelete = append(toDelete, c.liveMessages[0].MessageID)
+		for c.liveMessages.Len() > 0 && c.liveMessages.Front().PostedAt.Before(cutoff) {
+			front := c.liveMessages.Front()
+			if !c.keepLookup[front.MessageID] {
+				toDelete = append(toDelete, front.MessageID)
 				if oldest == zero {
-					oldest = c.liveMessages[0].PostedAt
+					oldest = front.PostedAt
 				}
 			}
-			c.liveMessages = c.liveMessages[1:]
+			c.liveMessages.PopFront()
 		}
 		// Collect additional messages within 1.5sec of deleted message
 		if oldest != zero {
 			cutoff = oldest.Add(1500 * time.Millisecond)
-			for len(c.liveMessages) > 0 && c.liveMessages[0].PostedAt.Before(cutoff) {
-				if !c.keepLookup[c.liveMessages[0].MessageID] {
-					toDelete = append(toDelete, c.liveMessages[0].MessageID)
+			for c.liveMessages.Len() > 0 && c.liveMessages.Front().PostedAt.Before(cutoff) {
+				front := c.liveMessages.Front()
+				if !c.keepLookup[front.MessageID] {
+					toDelete = append(toDelete, front.MessageID)
 				}
-				c.liveMessages = c.liveMessages[1:]
+				c.liveMessages.PopFront()
 			}
 		}
 	}
@@ -513,3 +692,73 @@ func (c *ManagedChannel) collectMessagesToDelete() (m []string, needsQueueBacklo
 	return toDelete, ((nLiveMessages >= backlogReloadLimit*backlogAutoReloadPreFraction) &&
 		(len(toDelete) > backlogReloadLimit*backlogAutoReloadDeleteFraction)), false
 }
+
+// filterNeedsFullMessage reports whether f inspects anything beyond a
+// message's ID and author ID - content, attachments, embeds, timestamp, or
+// the author's bot flag - none of which liveMessages retains. Unknown
+// filter types are assumed to need the full message, so ReapMatching fails
+// closed instead of silently matching everything.
+func filterNeedsFullMessage(f MessageFilter) bool {
+	switch v := f.(type) {
+	case *MessageAuthorFilter:
+		return false
+	case *InvertFilter:
+		return filterNeedsFullMessage(v.Filter)
+	default:
+		return true
+	}
+}
+
+// ReapMatching immediately deletes up to limit live messages for which
+// filter.ShouldKeep returns false (0 or negative limit means no cap). When
+// filter is a deny-list MessageAuthorFilter, candidates are pulled straight
+// from the author index instead of walking the whole backlog.
+//
+// filter only ever sees a synthetic message carrying the ID and author -
+// liveMessages doesn't retain content, attachments, or embeds - so it's an
+// error to pass a filter that needs any of that (e.g. RegexContentFilter);
+// use Clean for those instead.
+func (c *ManagedChannel) ReapMatching(filter MessageFilter, limit int) (int, error) {
+	if filterNeedsFullMessage(filter) {
+		return 0, fmt.Errorf("ReapMatching: %T needs message content/attachments/embeds/timestamp that the live-message cache doesn't retain; use Clean instead", filter)
+	}
+
+	c.mu.Lock()
+
+	var candidates []string
+	consider := func(m *smallMessage) bool {
+		if limit > 0 && len(candidates) >= limit {
+			return false
+		}
+		fake := &discordgo.Message{ID: m.MessageID}
+		if m.AuthorID != "" {
+			fake.Author = &discordgo.User{ID: m.AuthorID}
+		}
+		if !c.keepLookup[m.MessageID] && !filter.ShouldKeep(fake) {
+			candidates = append(candidates, m.MessageID)
+		}
+		return true
+	}
+
+	if af, ok := filter.(*MessageAuthorFilter); ok && !af.Allow {
+		for authorID := range af.UserIDs {
+			for _, m := range c.liveMessages.ForAuthor(authorID) {
+				if !consider(m) {
+					break
+				}
+			}
+		}
+	} else {
+		c.liveMessages.Range(consider)
+	}
+
+	for _, id := range candidates {
+		c.liveMessages.Remove(id)
+	}
+	c.mu.Unlock()
+
+	if len(candidates) == 0 {
+		return 0, nil
+	}
+	return c.Reap(candidates)
+}
@@ -0,0 +1,192 @@
+package autodelete
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// maxCleanPages bounds how many 100-message pages Clean will walk via
+// ChannelMessages before giving up, rather than an unbounded crawl of the
+// channel's entire history.
+const maxCleanPages = 50
+
+// CleanOptions configures a one-off ManagedChannel.Clean invocation, as
+// opposed to the channel's standing MessageLiveTime/MaxMessages reap rules.
+type CleanOptions struct {
+	// Count caps how many messages are deleted. <= 0 means 100.
+	Count           int
+	UserID          string
+	Regex           string
+	CaseInsensitive bool
+	HasAttachment   bool
+	HasEmbed        bool
+	// Before/After bound the message history scanned, same semantics as
+	// discordgo.Session.ChannelMessages - Discord's history endpoint treats
+	// them as mutually exclusive, so setting both is an error.
+	Before string
+	After  string
+	// Invert selects messages that do NOT match the other criteria.
+	Invert bool
+}
+
+// criteria builds the individual MessageFilters matching each non-zero
+// CleanOptions field, reusing the channel filter pipeline. Each returned
+// filter's ShouldKeep reports whether a message matches that criterion.
+func (o CleanOptions) criteria() ([]MessageFilter, error) {
+	var out []MessageFilter
+	if o.UserID != "" {
+		out = append(out, NewMessageAuthorFilter(true, o.UserID))
+	}
+	if o.Regex != "" {
+		rf, err := NewRegexContentFilter(o.Regex, o.CaseInsensitive)
+		if err != nil {
+			return nil, err
+		}
+		// RegexContentFilter.ShouldKeep is true for non-matches; invert it
+		// so true means "matches the clean criterion".
+		out = append(out, &InvertFilter{Filter: rf})
+	}
+	if o.HasAttachment {
+		out = append(out, HasAttachmentFilter{})
+	}
+	if o.HasEmbed {
+		out = append(out, HasEmbedFilter{})
+	}
+	return out, nil
+}
+
+// cleanFilter composes CleanOptions' criteria into a MessageFilter:
+// ShouldKeep is true for every message that should survive the clean.
+type cleanFilter struct {
+	criteria []MessageFilter
+	invert   bool
+}
+
+func (f *cleanFilter) ShouldKeep(m *discordgo.Message) bool {
+	matchesAll := true
+	for _, c := range f.criteria {
+		if !c.ShouldKeep(m) {
+			matchesAll = false
+			break
+		}
+	}
+	isTarget := matchesAll
+	if f.invert {
+		isTarget = !matchesAll
+	}
+	return !isTarget
+}
+
+// Clean deletes up to opts.Count messages matching opts, walking message
+// history via ChannelMessages in pages (up to maxCleanPages) rather than
+// relying on the channel's cached liveMessages window. Like Reap, it falls
+// back to single deletes once a message is older than Discord's 14-day bulk
+// delete cutoff.
+//
+// ctx bounds how long Clean may keep paging - a walk can take up to
+// maxCleanPages round trips - and is checked before each page fetch; a
+// canceled ctx stops the walk and deletes whatever was already collected.
+func (c *ManagedChannel) Clean(ctx context.Context, opts CleanOptions) (int, error) {
+	if opts.Before != "" && opts.After != "" {
+		return 0, fmt.Errorf("clean: before and after can't both be set - Discord's message history endpoint treats them as mutually exclusive")
+	}
+
+	criteria, err := opts.criteria()
+	if err != nil {
+		return 0, err
+	}
+	filter := &cleanFilter{criteria: criteria, invert: opts.Invert}
+
+	limit := opts.Count
+	if limit <= 0 {
+		limit = 100
+	}
+
+	var toDelete []string
+	before, after := opts.Before, opts.After
+	for page := 0; page < maxCleanPages && len(toDelete) < limit; page++ {
+		if ctx.Err() != nil {
+			break
+		}
+		msgs, err := c.bot.s.ChannelMessages(c.ChannelID, 100, before, after, "")
+		if err != nil {
+			return len(toDelete), err
+		}
+		if len(msgs) == 0 {
+			break
+		}
+
+		c.mu.Lock()
+		for _, m := range msgs {
+			if c.keepLookup[m.ID] {
+				continue
+			}
+			if !filter.ShouldKeep(m) {
+				toDelete = append(toDelete, m.ID)
+				if len(toDelete) >= limit {
+					break
+				}
+			}
+		}
+		c.mu.Unlock()
+
+		// Keep paging in whichever single direction the caller picked -
+		// sending both before and after on the same request is invalid.
+		if after != "" {
+			after = msgs[0].ID
+		} else {
+			before = msgs[len(msgs)-1].ID
+		}
+		if len(msgs) < 100 {
+			break
+		}
+	}
+
+	if len(toDelete) == 0 {
+		return 0, nil
+	}
+
+	deleted, err := c.Reap(toDelete)
+	if deleted > 0 {
+		c.mu.Lock()
+		for _, id := range toDelete[:deleted] {
+			c.liveMessages.Remove(id)
+		}
+		c.mu.Unlock()
+	}
+	return deleted, err
+}
+
+// cleanHandlerTimeout bounds how long a single CleanHandler request may run
+// a Clean walk for, independent of the caller's own request context.
+const cleanHandlerTimeout = 60 * time.Second
+
+// CleanHandler serves a one-off admin request that runs Clean synchronously,
+// decoding the body as CleanOptions. No slash-command dispatcher exists in
+// this tree yet to expose this as a Discord command.
+func (c *ManagedChannel) CleanHandler(w http.ResponseWriter, r *http.Request) {
+	var opts CleanOptions
+	if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), cleanHandlerTimeout)
+	defer cancel()
+
+	deleted, err := c.Clean(ctx, opts)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Deleted int `json:"deleted"`
+	}{Deleted: deleted})
+}
@@ -0,0 +1,355 @@
+package autodelete
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/raft"
+)
+
+const raftApplyTimeout = 5 * time.Second
+
+// clusterCommand is the tagged payload replicated through the Raft log.
+type clusterCommand struct {
+	Op        string                 `json:"op"` // "upsert_channel", "delete_channel", "mark_deleted"
+	Channel   *ManagedChannelMarshal `json:"channel,omitempty"`
+	ChannelID string                 `json:"channel_id,omitempty"`
+	MessageID string                 `json:"message_id,omitempty"`
+}
+
+const (
+	clusterOpUpsertChannel = "upsert_channel"
+	clusterOpDeleteChannel = "delete_channel"
+	clusterOpMarkDeleted   = "mark_deleted"
+)
+
+// clusterFSM is the hashicorp/raft state machine replicating the
+// authoritative ManagedChannelMarshal set plus the high-water mark of
+// deleted message IDs per channel, so a newly elected leader's warm
+// liveMessages cache doesn't re-delete messages the old leader already
+// reaped. Apply also installs/removes the live *ManagedChannel on bot, so
+// channel disable/delete takes effect on every node the same way - through
+// the log - instead of being a local mutation on Bot.channels.
+type clusterFSM struct {
+	bot *Bot
+
+	mu        sync.RWMutex
+	channels  map[string]ManagedChannelMarshal // keyed by ChannelID
+	highWater map[string]string                // ChannelID -> newest deleted message ID
+}
+
+func newClusterFSM(bot *Bot) *clusterFSM {
+	return &clusterFSM{
+		bot:       bot,
+		channels:  make(map[string]ManagedChannelMarshal),
+		highWater: make(map[string]string),
+	}
+}
+
+// highWaterFor returns the newest message ID already reaped for channelID,
+// or "" if none has been recorded.
+func (f *clusterFSM) highWaterFor(channelID string) string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.highWater[channelID]
+}
+
+func (f *clusterFSM) Apply(log *raft.Log) interface{} {
+	var cmd clusterCommand
+	if err := json.Unmarshal(log.Data, &cmd); err != nil {
+		return err
+	}
+
+	switch cmd.Op {
+	case clusterOpUpsertChannel:
+		if cmd.Channel == nil {
+			return fmt.Errorf("cluster: upsert_channel missing channel")
+		}
+		f.mu.Lock()
+		f.channels[cmd.Channel.ID] = *cmd.Channel
+		f.mu.Unlock()
+		// Runs on every node applying this log entry, including the one
+		// that originated it, so leadership changes don't leave a node's
+		// Bot.channels out of sync with the replicated config.
+		if err := f.bot.installChannel(*cmd.Channel); err != nil {
+			fmt.Println("[cluster] could not install channel", cmd.Channel.ID, err)
+		}
+	case clusterOpDeleteChannel:
+		f.mu.Lock()
+		delete(f.channels, cmd.ChannelID)
+		delete(f.highWater, cmd.ChannelID)
+		f.mu.Unlock()
+		f.bot.removeChannel(cmd.ChannelID)
+	case clusterOpMarkDeleted:
+		f.mu.Lock()
+		f.highWater[cmd.ChannelID] = cmd.MessageID
+		f.mu.Unlock()
+	default:
+		return fmt.Errorf("cluster: unknown command op %q", cmd.Op)
+	}
+	return nil
+}
+
+type clusterSnapshot struct {
+	Channels  map[string]ManagedChannelMarshal `json:"channels"`
+	HighWater map[string]string                `json:"high_water"`
+}
+
+func (f *clusterFSM) Snapshot() (raft.FSMSnapshot, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	snap := &clusterSnapshot{
+		Channels:  make(map[string]ManagedChannelMarshal, len(f.channels)),
+		HighWater: make(map[string]string, len(f.highWater)),
+	}
+	for k, v := range f.channels {
+		snap.Channels[k] = v
+	}
+	for k, v := range f.highWater {
+		snap.HighWater[k] = v
+	}
+	return snap, nil
+}
+
+func (s *clusterSnapshot) Persist(sink raft.SnapshotSink) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		sink.Cancel()
+		return err
+	}
+	if _, err := sink.Write(b); err != nil {
+		sink.Cancel()
+		return err
+	}
+	return sink.Close()
+}
+
+func (s *clusterSnapshot) Release() {}
+
+func (f *clusterFSM) Restore(rc io.ReadCloser) error {
+	defer rc.Close()
+	var snap clusterSnapshot
+	if err := json.NewDecoder(rc).Decode(&snap); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.channels = snap.Channels
+	f.highWater = snap.HighWater
+	return nil
+}
+
+// A Cluster makes a set of AutoDelete processes fault tolerant: only the
+// Raft leader runs the reap loop, channel disable/delete goes through the
+// Raft log rather than being a local mutation on Bot.channels (serializing
+// it against in-flight reaps), and followers keep a warm cache so failover
+// is sub-second.
+type Cluster struct {
+	bot  *Bot
+	raft *raft.Raft
+	fsm  *clusterFSM
+}
+
+func NewCluster(bot *Bot, r *raft.Raft, fsm *clusterFSM) *Cluster {
+	return &Cluster{bot: bot, raft: r, fsm: fsm}
+}
+
+func (cl *Cluster) IsLeader() bool {
+	return cl.raft.State() == raft.Leader
+}
+
+func (cl *Cluster) apply(cmd clusterCommand) error {
+	b, err := json.Marshal(cmd)
+	if err != nil {
+		return err
+	}
+	return cl.raft.Apply(b, raftApplyTimeout).Error()
+}
+
+// UpsertChannel replicates a channel's config to the cluster. Must be called
+// against the leader.
+func (cl *Cluster) UpsertChannel(conf ManagedChannelMarshal) error {
+	return cl.apply(clusterCommand{Op: clusterOpUpsertChannel, Channel: &conf})
+}
+
+// DeleteChannel replicates a channel's removal to the cluster. Must be
+// called against the leader.
+func (cl *Cluster) DeleteChannel(channelID string) error {
+	return cl.apply(clusterCommand{Op: clusterOpDeleteChannel, ChannelID: channelID})
+}
+
+// markDeleted records msgID as the newest reaped message in channelID, so a
+// newly elected leader doesn't double-delete it.
+func (cl *Cluster) markDeleted(channelID, msgID string) error {
+	return cl.apply(clusterCommand{Op: clusterOpMarkDeleted, ChannelID: channelID, MessageID: msgID})
+}
+
+// snowflakeNewer reports whether a is a numerically greater (i.e. newer)
+// Discord snowflake ID than b. A blank b (no prior high-water mark) makes
+// every a newer.
+func snowflakeNewer(a, b string) bool {
+	if b == "" {
+		return true
+	}
+	if len(a) != len(b) {
+		return len(a) > len(b)
+	}
+	return a > b
+}
+
+// ReapIfLeader runs c's normal collect-and-delete cycle only while this
+// process holds Raft leadership, then records the high-water mark so a
+// failover doesn't re-delete the same messages.
+//
+// collectMessagesToDelete always pops the messages it selects out of c's
+// warm cache, even the ones below the FSM's high-water mark - those were
+// already reaped by whichever node deleted them before, so dropping them
+// from the cache is correct, but re-sending the delete to Discord isn't.
+func (cl *Cluster) ReapIfLeader(c *ManagedChannel) {
+	if !cl.IsLeader() {
+		return
+	}
+
+	toDelete, needsQueueBacklog, isDisabled := c.collectMessagesToDelete()
+	if isDisabled {
+		return
+	}
+	if len(toDelete) > 0 {
+		hw := cl.fsm.highWaterFor(c.ChannelID)
+		newest := toDelete[len(toDelete)-1]
+
+		var pending []string
+		for _, id := range toDelete {
+			if snowflakeNewer(id, hw) {
+				pending = append(pending, id)
+			}
+		}
+
+		if len(pending) > 0 {
+			if _, err := c.Reap(pending); err != nil {
+				fmt.Println("[cluster] reap failed for", c, err)
+				return
+			}
+		}
+		if snowflakeNewer(newest, hw) {
+			if err := cl.markDeleted(c.ChannelID, newest); err != nil {
+				fmt.Println("[cluster] could not record high-water mark for", c, err)
+			}
+		}
+	}
+	if needsQueueBacklog {
+		cl.bot.QueueLoadBacklog(c, true)
+	}
+}
+
+type clusterStatus struct {
+	State    string `json:"state"`
+	LeaderID string `json:"leader_id"`
+	IsLeader bool   `json:"is_leader"`
+}
+
+// StatusHandler serves /cluster/status with this node's Raft leadership
+// state, for monitoring.
+func (cl *Cluster) StatusHandler(w http.ResponseWriter, r *http.Request) {
+	_, leaderID := cl.raft.LeaderWithID()
+	resp := clusterStatus{
+		State:    cl.raft.State().String(),
+		LeaderID: string(leaderID),
+		IsLeader: cl.IsLeader(),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// Bootstrap bootstraps a brand new single-node cluster; later nodes join it
+// with Join.
+func Bootstrap(r *raft.Raft, localID raft.ServerID, localAddr raft.ServerAddress) error {
+	cfg := raft.Configuration{
+		Servers: []raft.Server{{ID: localID, Address: localAddr}},
+	}
+	return r.BootstrapCluster(cfg).Error()
+}
+
+// Join adds a new voting peer to the cluster. Must be called against the
+// current leader.
+func Join(r *raft.Raft, id raft.ServerID, addr raft.ServerAddress) error {
+	return r.AddVoter(id, addr, 0, 0).Error()
+}
+
+// BootstrapHandler serves a POST /cluster/bootstrap admin request,
+// bootstrapping this node as a brand new single-node cluster.
+func (cl *Cluster) BootstrapHandler(w http.ResponseWriter, r *http.Request) {
+	id, addr := r.URL.Query().Get("id"), r.URL.Query().Get("addr")
+	if id == "" || addr == "" {
+		http.Error(w, "id and addr are required", http.StatusBadRequest)
+		return
+	}
+	if err := Bootstrap(cl.raft, raft.ServerID(id), raft.ServerAddress(addr)); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// JoinHandler serves a POST /cluster/join admin request adding a new
+// voting peer to the cluster. Must be called against the leader.
+func (cl *Cluster) JoinHandler(w http.ResponseWriter, r *http.Request) {
+	id, addr := r.URL.Query().Get("id"), r.URL.Query().Get("addr")
+	if id == "" || addr == "" {
+		http.Error(w, "id and addr are required", http.StatusBadRequest)
+		return
+	}
+	if err := Join(cl.raft, raft.ServerID(id), raft.ServerAddress(addr)); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// installChannel creates (or replaces) the live ManagedChannel for conf on
+// b and kicks off a backlog load, mirroring what the non-clustered path
+// does when a channel is configured locally. Called from clusterFSM.Apply
+// on every node replicating an upsert_channel entry, including the one
+// that originated it, so a pre-existing entry (e.g. a config update, not
+// just first-time registration) is disabled first - otherwise the orphaned
+// old ManagedChannel would keep its reap-queue registration and could
+// double-delete against the replacement.
+func (b *Bot) installChannel(conf ManagedChannelMarshal) error {
+	b.mu.RLock()
+	old := b.channels[conf.ID]
+	b.mu.RUnlock()
+	if old != nil {
+		old.Disable()
+	}
+
+	mc, err := InitChannel(b, conf)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	b.channels[mc.ChannelID] = mc
+	b.mu.Unlock()
+
+	go mc.LoadBacklogNow()
+	return nil
+}
+
+// removeChannel drops channelID's ManagedChannel from b, if live. This is
+// the Raft-driven counterpart to ManagedChannel.Disable's local-only
+// Bot.channels mutation, called from clusterFSM.Apply on every node
+// replicating a delete_channel entry.
+func (b *Bot) removeChannel(channelID string) {
+	b.mu.RLock()
+	mc := b.channels[channelID]
+	b.mu.RUnlock()
+	if mc != nil {
+		mc.Disable()
+	}
+}
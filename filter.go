@@ -0,0 +1,229 @@
+package autodelete
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// A MessageFilter decides whether a message should be kept (i.e. excluded
+// from autodeletion) on top of the channel's normal pin/keep-list handling.
+// ShouldKeep returning true protects the message the same way a pin does.
+type MessageFilter interface {
+	ShouldKeep(m *discordgo.Message) bool
+}
+
+// MessageAuthorFilter keeps or excludes messages based on the author's user
+// ID. If Allow is true, UserIDs is a protect-list: only messages from those
+// authors are kept. If Allow is false, it's the inverse: every author except
+// those listed is kept, so only the listed authors are left eligible for the
+// channel's normal reaping rules.
+type MessageAuthorFilter struct {
+	UserIDs map[string]bool
+	Allow   bool
+}
+
+func NewMessageAuthorFilter(allow bool, userIDs ...string) *MessageAuthorFilter {
+	lookup := make(map[string]bool, len(userIDs))
+	for _, id := range userIDs {
+		lookup[id] = true
+	}
+	return &MessageAuthorFilter{UserIDs: lookup, Allow: allow}
+}
+
+func (f *MessageAuthorFilter) ShouldKeep(m *discordgo.Message) bool {
+	if m.Author == nil {
+		return !f.Allow
+	}
+	matched := f.UserIDs[m.Author.ID]
+	if f.Allow {
+		return matched
+	}
+	return !matched
+}
+
+// RegexContentFilter keeps messages whose content does not match Pattern.
+type RegexContentFilter struct {
+	Pattern *regexp.Regexp
+}
+
+func NewRegexContentFilter(pattern string, caseInsensitive bool) (*RegexContentFilter, error) {
+	if caseInsensitive {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &RegexContentFilter{Pattern: re}, nil
+}
+
+func (f *RegexContentFilter) ShouldKeep(m *discordgo.Message) bool {
+	return !f.Pattern.MatchString(m.Content)
+}
+
+// BotOnlyFilter keeps messages not posted by bots (including messages with no
+// author), leaving only bot messages subject to the channel's normal reaping
+// rules.
+type BotOnlyFilter struct{}
+
+func (BotOnlyFilter) ShouldKeep(m *discordgo.Message) bool {
+	return m.Author == nil || !m.Author.Bot
+}
+
+// HasAttachmentFilter keeps messages that have at least one attachment.
+type HasAttachmentFilter struct{}
+
+func (HasAttachmentFilter) ShouldKeep(m *discordgo.Message) bool {
+	return len(m.Attachments) > 0
+}
+
+// HasEmbedFilter keeps messages that have at least one embed.
+type HasEmbedFilter struct{}
+
+func (HasEmbedFilter) ShouldKeep(m *discordgo.Message) bool {
+	return len(m.Embeds) > 0
+}
+
+// MinAgeFilter keeps messages younger than MinAge, so freshly posted
+// messages survive even if a channel's other rules would otherwise reap
+// them immediately.
+type MinAgeFilter struct {
+	MinAge time.Duration
+}
+
+func (f *MinAgeFilter) ShouldKeep(m *discordgo.Message) bool {
+	ts, err := m.Timestamp.Parse()
+	if err != nil || ts.IsZero() {
+		return true
+	}
+	return time.Since(ts) < f.MinAge
+}
+
+// InvertFilter flips the result of the wrapped filter.
+type InvertFilter struct {
+	Filter MessageFilter
+}
+
+func (f *InvertFilter) ShouldKeep(m *discordgo.Message) bool {
+	return !f.Filter.ShouldKeep(m)
+}
+
+// FilterMarshal is the tagged JSON representation of a MessageFilter, used
+// to persist a channel's filter chain in ManagedChannelMarshal.
+type FilterMarshal struct {
+	Type            string         `json:"type"`
+	UserIDs         []string       `json:"user_ids,omitempty"`
+	Allow           bool           `json:"allow,omitempty"`
+	Pattern         string         `json:"pattern,omitempty"`
+	CaseInsensitive bool           `json:"case_insensitive,omitempty"`
+	MinAge          time.Duration  `json:"min_age,omitempty"`
+	Invert          *FilterMarshal `json:"invert,omitempty"`
+}
+
+const (
+	filterTypeAuthor        = "author"
+	filterTypeRegex         = "regex"
+	filterTypeBotOnly       = "bot_only"
+	filterTypeHasAttachment = "has_attachment"
+	filterTypeHasEmbed      = "has_embed"
+	filterTypeMinAge        = "min_age"
+	filterTypeInvert        = "invert"
+)
+
+func (fm FilterMarshal) toFilter() (MessageFilter, error) {
+	switch fm.Type {
+	case filterTypeAuthor:
+		return NewMessageAuthorFilter(fm.Allow, fm.UserIDs...), nil
+	case filterTypeRegex:
+		return NewRegexContentFilter(fm.Pattern, fm.CaseInsensitive)
+	case filterTypeBotOnly:
+		return BotOnlyFilter{}, nil
+	case filterTypeHasAttachment:
+		return HasAttachmentFilter{}, nil
+	case filterTypeHasEmbed:
+		return HasEmbedFilter{}, nil
+	case filterTypeMinAge:
+		return &MinAgeFilter{MinAge: fm.MinAge}, nil
+	case filterTypeInvert:
+		if fm.Invert == nil {
+			return nil, fmt.Errorf("invert filter missing wrapped filter")
+		}
+		wrapped, err := fm.Invert.toFilter()
+		if err != nil {
+			return nil, err
+		}
+		return &InvertFilter{Filter: wrapped}, nil
+	default:
+		return nil, fmt.Errorf("unknown filter type %q", fm.Type)
+	}
+}
+
+func unmarshalFilters(raw []FilterMarshal) ([]MessageFilter, error) {
+	filters := make([]MessageFilter, 0, len(raw))
+	for _, fm := range raw {
+		f, err := fm.toFilter()
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	return filters, nil
+}
+
+func toFilterMarshal(f MessageFilter) FilterMarshal {
+	switch v := f.(type) {
+	case *MessageAuthorFilter:
+		ids := make([]string, 0, len(v.UserIDs))
+		for id := range v.UserIDs {
+			ids = append(ids, id)
+		}
+		return FilterMarshal{Type: filterTypeAuthor, UserIDs: ids, Allow: v.Allow}
+	case *RegexContentFilter:
+		return FilterMarshal{Type: filterTypeRegex, Pattern: v.Pattern.String()}
+	case BotOnlyFilter:
+		return FilterMarshal{Type: filterTypeBotOnly}
+	case HasAttachmentFilter:
+		return FilterMarshal{Type: filterTypeHasAttachment}
+	case HasEmbedFilter:
+		return FilterMarshal{Type: filterTypeHasEmbed}
+	case *MinAgeFilter:
+		return FilterMarshal{Type: filterTypeMinAge, MinAge: v.MinAge}
+	case *InvertFilter:
+		inner := toFilterMarshal(v.Filter)
+		return FilterMarshal{Type: filterTypeInvert, Invert: &inner}
+	default:
+		return FilterMarshal{}
+	}
+}
+
+func marshalFilters(filters []MessageFilter) []FilterMarshal {
+	out := make([]FilterMarshal, 0, len(filters))
+	for _, f := range filters {
+		out = append(out, toFilterMarshal(f))
+	}
+	return out
+}
+
+// SetFiltersHandler serves a one-off admin request that replaces a
+// channel's filter chain, decoding the body as a []FilterMarshal the same
+// shape Export().Filters produces. No slash-command dispatcher exists in
+// this tree yet to expose this as a Discord command.
+func (c *ManagedChannel) SetFiltersHandler(w http.ResponseWriter, r *http.Request) {
+	var raw []FilterMarshal
+	if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filters, err := unmarshalFilters(raw)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.SetFilters(filters)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,104 @@
+package autodelete
+
+import "container/list"
+
+// messageIndex keeps the chronological order of a channel's live messages
+// (oldest at the front) alongside secondary indices by message ID and by
+// author, so that ID lookups and author-scoped queries don't require a full
+// scan of the backlog.
+type messageIndex struct {
+	order    *list.List // of *smallMessage
+	byID     map[string]*list.Element
+	byAuthor map[string][]*list.Element
+}
+
+func newMessageIndex() *messageIndex {
+	return &messageIndex{
+		order:    list.New(),
+		byID:     make(map[string]*list.Element),
+		byAuthor: make(map[string][]*list.Element),
+	}
+}
+
+func (idx *messageIndex) Len() int {
+	return idx.order.Len()
+}
+
+// PushBack adds m as the newest live message.
+func (idx *messageIndex) PushBack(m smallMessage) {
+	el := idx.order.PushBack(&m)
+	idx.byID[m.MessageID] = el
+	if m.AuthorID != "" {
+		idx.byAuthor[m.AuthorID] = append(idx.byAuthor[m.AuthorID], el)
+	}
+}
+
+// Front returns the oldest live message, or nil if there are none.
+func (idx *messageIndex) Front() *smallMessage {
+	el := idx.order.Front()
+	if el == nil {
+		return nil
+	}
+	return el.Value.(*smallMessage)
+}
+
+// PopFront removes and returns the oldest live message, or nil if there are
+// none.
+func (idx *messageIndex) PopFront() *smallMessage {
+	el := idx.order.Front()
+	if el == nil {
+		return nil
+	}
+	idx.removeElement(el)
+	return el.Value.(*smallMessage)
+}
+
+// Remove deletes the message with the given ID from every index, returning
+// it, or nil if it wasn't live.
+func (idx *messageIndex) Remove(msgID string) *smallMessage {
+	el, ok := idx.byID[msgID]
+	if !ok {
+		return nil
+	}
+	idx.removeElement(el)
+	return el.Value.(*smallMessage)
+}
+
+func (idx *messageIndex) removeElement(el *list.Element) {
+	m := el.Value.(*smallMessage)
+	idx.order.Remove(el)
+	delete(idx.byID, m.MessageID)
+
+	if m.AuthorID == "" {
+		return
+	}
+	bucket := idx.byAuthor[m.AuthorID]
+	for i, e := range bucket {
+		if e == el {
+			idx.byAuthor[m.AuthorID] = append(bucket[:i], bucket[i+1:]...)
+			break
+		}
+	}
+	if len(idx.byAuthor[m.AuthorID]) == 0 {
+		delete(idx.byAuthor, m.AuthorID)
+	}
+}
+
+// ForAuthor returns the live messages posted by authorID, oldest first.
+func (idx *messageIndex) ForAuthor(authorID string) []*smallMessage {
+	bucket := idx.byAuthor[authorID]
+	out := make([]*smallMessage, 0, len(bucket))
+	for _, el := range bucket {
+		out = append(out, el.Value.(*smallMessage))
+	}
+	return out
+}
+
+// Range walks the index oldest first, stopping early if fn returns false.
+func (idx *messageIndex) Range(fn func(m *smallMessage) bool) {
+	for el := idx.order.Front(); el != nil; el = el.Next() {
+		if !fn(el.Value.(*smallMessage)) {
+			return
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package autodelete
+
+import (
+	"testing"
+	"time"
+)
+
+func msg(id, author string) smallMessage {
+	return smallMessage{MessageID: id, AuthorID: author, PostedAt: time.Now()}
+}
+
+func rangeIDs(idx *messageIndex) []string {
+	var ids []string
+	idx.Range(func(m *smallMessage) bool {
+		ids = append(ids, m.MessageID)
+		return true
+	})
+	return ids
+}
+
+func TestMessageIndexPushBackOrder(t *testing.T) {
+	idx := newMessageIndex()
+	idx.PushBack(msg("1", "a"))
+	idx.PushBack(msg("2", "b"))
+	idx.PushBack(msg("3", "a"))
+
+	if got := idx.Len(); got != 3 {
+		t.Fatalf("Len() = %d, want 3", got)
+	}
+	if got := idx.Front().MessageID; got != "1" {
+		t.Fatalf("Front().MessageID = %q, want %q", got, "1")
+	}
+	if got := rangeIDs(idx); !equalIDs(got, []string{"1", "2", "3"}) {
+		t.Fatalf("Range order = %v, want [1 2 3]", got)
+	}
+}
+
+func TestMessageIndexPopFront(t *testing.T) {
+	idx := newMessageIndex()
+	idx.PushBack(msg("1", "a"))
+	idx.PushBack(msg("2", "b"))
+
+	popped := idx.PopFront()
+	if popped == nil || popped.MessageID != "1" {
+		t.Fatalf("PopFront() = %v, want message 1", popped)
+	}
+	if got := idx.Len(); got != 1 {
+		t.Fatalf("Len() after PopFront = %d, want 1", got)
+	}
+	if got := idx.Front().MessageID; got != "2" {
+		t.Fatalf("Front().MessageID after PopFront = %q, want %q", got, "2")
+	}
+	if got := idx.Remove("1"); got != nil {
+		t.Fatalf("Remove(%q) after PopFront = %v, want nil (already popped)", "1", got)
+	}
+
+	idx.PopFront()
+	if got := idx.PopFront(); got != nil {
+		t.Fatalf("PopFront() on empty index = %v, want nil", got)
+	}
+}
+
+func TestMessageIndexRemoveMiddle(t *testing.T) {
+	idx := newMessageIndex()
+	idx.PushBack(msg("1", "a"))
+	idx.PushBack(msg("2", "a"))
+	idx.PushBack(msg("3", "a"))
+
+	removed := idx.Remove("2")
+	if removed == nil || removed.MessageID != "2" {
+		t.Fatalf("Remove(%q) = %v, want message 2", "2", removed)
+	}
+	if got := idx.Len(); got != 2 {
+		t.Fatalf("Len() after Remove = %d, want 2", got)
+	}
+	if got := rangeIDs(idx); !equalIDs(got, []string{"1", "3"}) {
+		t.Fatalf("Range order after Remove = %v, want [1 3]", got)
+	}
+	if got := idx.ForAuthor("a"); len(got) != 2 {
+		t.Fatalf("ForAuthor(%q) after Remove = %v, want 2 entries", "a", got)
+	}
+}
+
+func TestMessageIndexRemoveUnknown(t *testing.T) {
+	idx := newMessageIndex()
+	idx.PushBack(msg("1", "a"))
+
+	if got := idx.Remove("does-not-exist"); got != nil {
+		t.Fatalf("Remove of unknown ID = %v, want nil", got)
+	}
+	if got := idx.Len(); got != 1 {
+		t.Fatalf("Len() unaffected by unknown Remove = %d, want 1", got)
+	}
+}
+
+func TestMessageIndexForAuthorBucketCleanup(t *testing.T) {
+	idx := newMessageIndex()
+	idx.PushBack(msg("1", "a"))
+	idx.PushBack(msg("2", "a"))
+	idx.PushBack(msg("3", "b"))
+
+	if got := idx.ForAuthor("a"); len(got) != 2 || got[0].MessageID != "1" || got[1].MessageID != "2" {
+		t.Fatalf("ForAuthor(%q) = %v, want [1 2] in order", "a", got)
+	}
+
+	idx.Remove("1")
+	idx.Remove("2")
+	if got := idx.ForAuthor("a"); len(got) != 0 {
+		t.Fatalf("ForAuthor(%q) after removing all its messages = %v, want empty", "a", got)
+	}
+	if _, ok := idx.byAuthor["a"]; ok {
+		t.Fatalf("byAuthor[%q] bucket should be deleted once empty, still present", "a")
+	}
+	if got := idx.ForAuthor("b"); len(got) != 1 {
+		t.Fatalf("ForAuthor(%q) = %v, want 1 entry", "b", got)
+	}
+}
+
+func TestMessageIndexPushBackNoAuthor(t *testing.T) {
+	idx := newMessageIndex()
+	idx.PushBack(msg("1", ""))
+
+	if got := idx.ForAuthor(""); len(got) != 0 {
+		t.Fatalf("ForAuthor(\"\") = %v, want empty; blank AuthorID shouldn't be indexed", got)
+	}
+	if got := idx.Remove("1"); got == nil || got.MessageID != "1" {
+		t.Fatalf("Remove(%q) = %v, want message 1", "1", got)
+	}
+}
+
+func TestMessageIndexRangeEarlyStop(t *testing.T) {
+	idx := newMessageIndex()
+	idx.PushBack(msg("1", "a"))
+	idx.PushBack(msg("2", "a"))
+	idx.PushBack(msg("3", "a"))
+
+	var seen []string
+	idx.Range(func(m *smallMessage) bool {
+		seen = append(seen, m.MessageID)
+		return m.MessageID != "2"
+	})
+	if !equalIDs(seen, []string{"1", "2"}) {
+		t.Fatalf("Range with early stop saw %v, want [1 2]", seen)
+	}
+}
+
+func equalIDs(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
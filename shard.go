@@ -0,0 +1,249 @@
+package autodelete
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// ShardForGuild returns the shard ID responsible for guildID under Discord's
+// sharding formula: (guildID >> 22) % shardCount.
+func ShardForGuild(guildID string, shardCount int) (int, error) {
+	id, err := strconv.ParseInt(guildID, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("shard: could not parse guild ID %q: %w", guildID, err)
+	}
+	return int((id >> 22) % int64(shardCount)), nil
+}
+
+// A ShardManager owns one Bot (and its discordgo.Session) per shard and
+// routes guild-scoped work to whichever shard owns that guild, so no single
+// Bot.mu has to serialize every guild in a large deployment.
+type ShardManager struct {
+	mu     sync.RWMutex
+	shards []*Bot
+}
+
+// NewShardManager wraps an already-connected Bot per shard, indexed by shard
+// ID.
+func NewShardManager(shards []*Bot) *ShardManager {
+	return &ShardManager{shards: shards}
+}
+
+func (sm *ShardManager) ShardCount() int {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	return len(sm.shards)
+}
+
+// BotForGuild returns the Bot owning guildID's shard.
+func (sm *ShardManager) BotForGuild(guildID string) (*Bot, error) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	id, err := ShardForGuild(guildID, len(sm.shards))
+	if err != nil {
+		return nil, err
+	}
+	b := sm.shards[id]
+	if b == nil {
+		return nil, fmt.Errorf("shard: shard %d has no Bot", id)
+	}
+	return b, nil
+}
+
+// InitChannel routes to InitChannel on the Bot owning guildID.
+func (sm *ShardManager) InitChannel(guildID string, chConf ManagedChannelMarshal) (*ManagedChannel, error) {
+	b, err := sm.BotForGuild(guildID)
+	if err != nil {
+		return nil, err
+	}
+	return InitChannel(b, chConf)
+}
+
+// Channel routes to Bot.Channel on the shard owning guildID.
+func (sm *ShardManager) Channel(guildID, channelID string) (*discordgo.Channel, error) {
+	b, err := sm.BotForGuild(guildID)
+	if err != nil {
+		return nil, err
+	}
+	return b.Channel(channelID)
+}
+
+// LoadAllBacklogs tells every shard's Bot to load all of its channels'
+// backlogs.
+func (sm *ShardManager) LoadAllBacklogs() {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	for _, b := range sm.shards {
+		if b != nil {
+			b.LoadAllBacklogs()
+		}
+	}
+}
+
+// QueueReap routes to QueueReap on the shard owning guildID.
+func (sm *ShardManager) QueueReap(guildID string, c *ManagedChannel) error {
+	b, err := sm.BotForGuild(guildID)
+	if err != nil {
+		return err
+	}
+	b.QueueReap(c)
+	return nil
+}
+
+// QueueLoadBacklog routes to QueueLoadBacklog on the shard owning guildID.
+func (sm *ShardManager) QueueLoadBacklog(guildID string, c *ManagedChannel, force bool) error {
+	b, err := sm.BotForGuild(guildID)
+	if err != nil {
+		return err
+	}
+	b.QueueLoadBacklog(c, force)
+	return nil
+}
+
+// SetShardCount grows or shrinks the manager's shard slots, e.g. when the
+// deployment's shard-count changes and channel ownership needs to be
+// recomputed against the new ShardForGuild results. New slots start nil
+// until a Bot is attached with ReplaceShard; shrinking drops the trailing
+// slots (and closes their Bots), so move channels off them first.
+func (sm *ShardManager) SetShardCount(n int) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if n == len(sm.shards) {
+		return
+	}
+	keep := n
+	if len(sm.shards) < keep {
+		keep = len(sm.shards)
+	}
+	grown := make([]*Bot, n)
+	copy(grown, sm.shards[:keep])
+	for _, dropped := range sm.shards[keep:] {
+		if dropped != nil {
+			dropped.s.Close()
+		}
+	}
+	sm.shards = grown
+}
+
+// MoveChannel hands conf's channel off from its current owner (fromShardID,
+// or -1 if it isn't live anywhere yet) to whichever shard ShardForGuild
+// names for conf.GuildID under the manager's current ShardCount. This is
+// the actual rebalance mechanism /shard/for-guild alone can't provide: it
+// disables the channel on its old shard's Bot and re-initializes it on the
+// new one.
+func (sm *ShardManager) MoveChannel(conf ManagedChannelMarshal, fromShardID int) (*ManagedChannel, error) {
+	sm.mu.RLock()
+	var from *Bot
+	if fromShardID >= 0 && fromShardID < len(sm.shards) {
+		from = sm.shards[fromShardID]
+	}
+	sm.mu.RUnlock()
+
+	if from != nil {
+		from.mu.RLock()
+		old := from.channels[conf.ID]
+		from.mu.RUnlock()
+		if old != nil {
+			old.Disable()
+		}
+	}
+
+	to, err := sm.BotForGuild(conf.GuildID)
+	if err != nil {
+		return nil, err
+	}
+	mc, err := InitChannel(to, conf)
+	if err != nil {
+		return nil, err
+	}
+
+	to.mu.Lock()
+	to.channels[mc.ChannelID] = mc
+	to.mu.Unlock()
+
+	go mc.LoadBacklogNow()
+	fmt.Printf("[shard] moved %s from shard %d to new owner\n", mc, fromShardID)
+	return mc, nil
+}
+
+// ReplaceShard gracefully closes the current Bot for shardID (if any) and
+// installs newBot in its place, e.g. after a shard's session needed to be
+// restarted.
+func (sm *ShardManager) ReplaceShard(shardID int, newBot *Bot) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if shardID < 0 || shardID >= len(sm.shards) {
+		return
+	}
+	if old := sm.shards[shardID]; old != nil {
+		old.s.Close()
+	}
+	sm.shards[shardID] = newBot
+	fmt.Printf("[shard] shard %d replaced\n", shardID)
+}
+
+// ShardConfig holds the --shard-id/--shard-count flags for running one
+// AutoDelete process per shard.
+type ShardConfig struct {
+	ShardID    int
+	ShardCount int
+}
+
+// RegisterFlags registers the shard flags on fs, defaulting to a single
+// unsharded process.
+func (sc *ShardConfig) RegisterFlags(fs *flag.FlagSet) {
+	fs.IntVar(&sc.ShardID, "shard-id", 0, "this process's shard ID")
+	fs.IntVar(&sc.ShardCount, "shard-count", 1, "total number of shards across all processes")
+}
+
+// ServeControlPlane registers the HTTP control plane on mux: /shard/for-guild
+// for asking which shard owns a guild, /shard/set-count to update the
+// shard map's size, and /shard/move-channel to actually hand a channel's
+// ownership from one shard to another once the map has changed.
+func (sm *ShardManager) ServeControlPlane(mux *http.ServeMux) {
+	mux.HandleFunc("/shard/for-guild", func(w http.ResponseWriter, r *http.Request) {
+		guildID := r.URL.Query().Get("guild_id")
+		id, err := ShardForGuild(guildID, sm.ShardCount())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintf(w, "%d", id)
+	})
+
+	mux.HandleFunc("/shard/set-count", func(w http.ResponseWriter, r *http.Request) {
+		n, err := strconv.Atoi(r.URL.Query().Get("count"))
+		if err != nil || n <= 0 {
+			http.Error(w, "count must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		sm.SetShardCount(n)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("/shard/move-channel", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Channel     ManagedChannelMarshal `json:"channel"`
+			FromShardID int                   `json:"from_shard_id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mc, err := sm.MoveChannel(req.Channel, req.FromShardID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(struct {
+			ChannelID string `json:"channel_id"`
+		}{ChannelID: mc.ChannelID})
+	})
+}